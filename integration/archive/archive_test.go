@@ -0,0 +1,186 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package archive verifies compatibility with the streaming archive format
+// produced and consumed by `mongodump --archive` / `mongorestore --archive`.
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/FerretDB/FerretDB/integration/setup"
+	"github.com/FerretDB/FerretDB/integration/shareddata"
+)
+
+// archiveCollections are the collections populated before dumping, and
+// restored into fresh collections after the round trip. Each also gets a
+// secondary index on "v", so the suite can assert index metadata survives
+// the round trip too.
+var archiveCollections = []struct {
+	name     string
+	provider shareddata.Provider
+}{
+	{"archive_scalars", shareddata.Scalars},
+	{"archive_composites", shareddata.Composites},
+}
+
+// requireMongoTools skips the test if the `mongodump`/`mongorestore`
+// binaries are not available on PATH, as they are not part of the Go
+// toolchain and must be installed separately in CI and locally.
+func requireMongoTools(t *testing.T) {
+	t.Helper()
+
+	for _, bin := range []string{"mongodump", "mongorestore"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not found in PATH, skipping", bin)
+		}
+	}
+}
+
+// sortByID sorts docs by their "_id" field so that Find results (whose order
+// isn't guaranteed to be stable, especially right after a restore) can be
+// compared positionally.
+func sortByID(docs []bson.D) {
+	sort.Slice(docs, func(i, j int) bool {
+		return fmt.Sprint(docs[i].Map()["_id"]) < fmt.Sprint(docs[j].Map()["_id"])
+	})
+}
+
+// indexKeyPatterns extracts the "key" document of every index returned by
+// Indexes().List, keyed by index name.
+func indexKeyPatterns(t *testing.T, indexes []bson.D) map[string]bson.D {
+	t.Helper()
+
+	res := make(map[string]bson.D, len(indexes))
+
+	for _, idx := range indexes {
+		m := idx.Map()
+
+		name, ok := m["name"].(string)
+		require.True(t, ok)
+
+		key, ok := m["key"].(bson.D)
+		require.True(t, ok)
+
+		res[name] = key
+	}
+
+	return res
+}
+
+// TestArchiveRoundTrip populates several collections, each with a secondary
+// index, dumps the test's own database with `mongodump --archive=-`, drops
+// the collections, restores them with `mongorestore --archive=-` from the
+// same stream, and asserts that every document and index definition survived
+// the round trip. The dump and restore are scoped to the database SetupWithOpts
+// created, since the deployment under test is shared with every other test
+// running in parallel.
+func TestArchiveRoundTrip(t *testing.T) {
+	setup.SkipForTigris(t)
+	requireMongoTools(t)
+
+	t.Parallel()
+
+	s := setup.SetupWithOpts(t, &setup.SetupOpts{})
+	ctx, collection := s.Ctx, s.Collection
+	db := collection.Database()
+
+	expectedDocs := make(map[string][]bson.D, len(archiveCollections))
+	expectedIndexes := make(map[string]map[string]bson.D, len(archiveCollections))
+
+	for _, c := range archiveCollections {
+		coll := db.Collection(c.name)
+
+		docs := c.provider.Docs()
+		insertDocs := make([]any, len(docs))
+
+		for i, d := range docs {
+			insertDocs[i] = d
+		}
+
+		_, err := coll.InsertMany(ctx, insertDocs)
+		require.NoError(t, err)
+
+		_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{"v", 1}}})
+		require.NoError(t, err)
+
+		sortByID(docs)
+		expectedDocs[c.name] = docs
+
+		cur, err := coll.Indexes().List(ctx)
+		require.NoError(t, err)
+
+		var indexes []bson.D
+		require.NoError(t, cur.All(ctx, &indexes))
+		expectedIndexes[c.name] = indexKeyPatterns(t, indexes)
+	}
+
+	// mongodump multiplexes every namespace onto a single stream: a magic
+	// header, a JSON prelude describing each namespace's metadata and
+	// indexes, then length-prefixed BSON body blocks tagged with
+	// {ns, eof, crc} namespace headers, ending in a terminator sentinel.
+	var archive bytes.Buffer
+
+	// Scope both sides of the round trip to this test's own database: the
+	// deployment under test is shared with every other test running in
+	// parallel, so an unscoped dump/restore would capture and drop
+	// databases this test doesn't own.
+	dbName := db.Name()
+
+	dump := exec.CommandContext(ctx, "mongodump", "--uri="+s.MongoDBURI, "--db="+dbName, "--archive=-")
+	dump.Stdout = &archive
+	require.NoError(t, dump.Run())
+
+	for _, c := range archiveCollections {
+		require.NoError(t, db.Collection(c.name).Drop(ctx))
+	}
+
+	restore := exec.CommandContext(
+		ctx, "mongorestore", "--uri="+s.MongoDBURI, "--nsInclude="+dbName+".*", "--archive=-", "--drop",
+	)
+	restore.Stdin = bytes.NewReader(archive.Bytes())
+	require.NoError(t, restore.Run())
+
+	for _, c := range archiveCollections {
+		var actual []bson.D
+		cur, err := db.Collection(c.name).Find(ctx, bson.D{})
+		require.NoError(t, err)
+		require.NoError(t, cur.All(ctx, &actual))
+
+		sortByID(actual)
+
+		expected := expectedDocs[c.name]
+		require.Len(t, actual, len(expected))
+
+		for i, exp := range expected {
+			require.Equal(t, exp.Map(), actual[i].Map())
+		}
+
+		cur, err = db.Collection(c.name).Indexes().List(ctx)
+		require.NoError(t, err)
+
+		var restoredIndexes []bson.D
+		require.NoError(t, cur.All(ctx, &restoredIndexes))
+
+		require.Equal(t, expectedIndexes[c.name], indexKeyPatterns(t, restoredIndexes), "restored index key patterns must match the originals")
+	}
+}
@@ -0,0 +1,215 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+
+	"github.com/FerretDB/FerretDB/integration/setup"
+	"github.com/FerretDB/FerretDB/integration/shareddata"
+)
+
+func TestAggregateStagesBucketAuto(t *testing.T) {
+	setup.SkipForTigris(t)
+
+	t.Parallel()
+
+	ctx, collection := setup.Setup(t, shareddata.Scalars)
+
+	total, err := collection.CountDocuments(ctx, bson.D{})
+	require.NoError(t, err)
+	require.Greater(t, total, int64(0))
+
+	for name, tc := range map[string]struct {
+		pipeline bson.A
+	}{
+		"TwoBuckets": {
+			pipeline: bson.A{
+				bson.D{{"$bucketAuto", bson.D{
+					{"groupBy", "$_id"},
+					{"buckets", int32(2)},
+				}}},
+			},
+		},
+		"FourBuckets": {
+			pipeline: bson.A{
+				bson.D{{"$bucketAuto", bson.D{
+					{"groupBy", "$_id"},
+					{"buckets", int32(4)},
+				}}},
+			},
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			cursor, err := collection.Aggregate(ctx, tc.pipeline)
+			require.NoError(t, err)
+
+			var res []bson.D
+			err = cursor.All(ctx, &res)
+			require.NoError(t, err)
+
+			require.NotEmpty(t, res)
+
+			var countSum int32
+			var prevMax string
+
+			for i, bucket := range res {
+				m := bucket.Map()
+
+				id, ok := m["_id"].(bson.D)
+				require.True(t, ok)
+
+				idMap := id.Map()
+				require.Contains(t, idMap, "min")
+				require.Contains(t, idMap, "max")
+
+				// shareddata.Scalars uses string _id values, so buckets sort
+				// lexicographically; assert the boundaries are non-decreasing.
+				min, ok := idMap["min"].(string)
+				require.True(t, ok)
+				max, ok := idMap["max"].(string)
+				require.True(t, ok)
+
+				if i > 0 {
+					require.LessOrEqual(t, prevMax, min, "bucket boundaries must be monotonically non-decreasing")
+				}
+				prevMax = max
+
+				count, ok := m["count"].(int32)
+				require.True(t, ok)
+				require.Greater(t, count, int32(0))
+
+				countSum += count
+			}
+
+			require.EqualValues(t, total, countSum)
+		})
+	}
+
+	t.Run("InvalidBuckets", func(t *testing.T) {
+		t.Parallel()
+
+		pipeline := bson.A{
+			bson.D{{"$bucketAuto", bson.D{
+				{"groupBy", "$_id"},
+				{"buckets", int32(0)},
+			}}},
+		}
+
+		_, err := collection.Aggregate(ctx, pipeline)
+		require.Error(t, err)
+	})
+
+	t.Run("Output", func(t *testing.T) {
+		t.Parallel()
+
+		pipeline := bson.A{
+			bson.D{{"$bucketAuto", bson.D{
+				{"groupBy", "$_id"},
+				{"buckets", int32(3)},
+				{"output", bson.D{
+					{"ids", bson.D{{"$push", "$_id"}}},
+					{"first", bson.D{{"$first", "$_id"}}},
+					{"last", bson.D{{"$last", "$_id"}}},
+				}},
+			}}},
+		}
+
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		require.NoError(t, err)
+
+		var res []bson.D
+		require.NoError(t, cursor.All(ctx, &res))
+		require.NotEmpty(t, res)
+
+		for _, bucket := range res {
+			m := bucket.Map()
+
+			count, ok := m["count"].(int32)
+			require.True(t, ok)
+
+			ids, ok := m["ids"].(bson.A)
+			require.True(t, ok)
+			require.EqualValues(t, count, len(ids))
+
+			first, ok := m["first"].(string)
+			require.True(t, ok)
+			last, ok := m["last"].(string)
+			require.True(t, ok)
+
+			require.Equal(t, ids[0], first)
+			require.Equal(t, ids[len(ids)-1], last)
+		}
+	})
+
+	t.Run("Granularity", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, collection := setup.Setup(t)
+
+		const docsCount = 20
+
+		docs := make([]any, docsCount)
+		for i := 0; i < docsCount; i++ {
+			docs[i] = bson.D{{"_id", int32(i)}, {"v", int32((i + 1) * 10)}}
+		}
+
+		_, err := collection.InsertMany(ctx, docs)
+		require.NoError(t, err)
+
+		pipeline := bson.A{
+			bson.D{{"$bucketAuto", bson.D{
+				{"groupBy", "$v"},
+				{"buckets", int32(4)},
+				{"granularity", "POWERSOF2"},
+			}}},
+		}
+
+		cursor, err := collection.Aggregate(ctx, pipeline)
+		require.NoError(t, err)
+
+		var res []bson.D
+		require.NoError(t, cursor.All(ctx, &res))
+		require.NotEmpty(t, res)
+
+		for _, bucket := range res {
+			m := bucket.Map()
+
+			id, ok := m["_id"].(bson.D)
+			require.True(t, ok)
+
+			idMap := id.Map()
+
+			minV, ok := idMap["min"].(int64)
+			require.True(t, ok)
+			maxV, ok := idMap["max"].(int64)
+			require.True(t, ok)
+
+			require.True(t, isPowerOfTwo(minV), "min %d must be a power of two", minV)
+			require.True(t, isPowerOfTwo(maxV), "max %d must be a power of two", maxV)
+		}
+	})
+}
+
+// isPowerOfTwo reports whether n is a positive power of two.
+func isPowerOfTwo(n int64) bool {
+	return n > 0 && n&(n-1) == 0
+}
@@ -0,0 +1,144 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package integration
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"github.com/FerretDB/FerretDB/integration/setup"
+)
+
+func TestSplitVector(t *testing.T) {
+	setup.SkipForTigris(t)
+
+	t.Parallel()
+
+	ctx, collection := setup.Setup(t)
+
+	const docsCount = 100
+
+	docs := make([]any, docsCount)
+	for i := 0; i < docsCount; i++ {
+		docs[i] = bson.D{{"_id", int32(i)}, {"v", fmt.Sprintf("value-%03d", i)}}
+	}
+
+	_, err := collection.InsertMany(ctx, docs)
+	require.NoError(t, err)
+
+	ns := collection.Database().Name() + "." + collection.Name()
+
+	for name, tc := range map[string]struct {
+		maxChunkSizeBytes int32
+		wantEmpty         bool
+	}{
+		"SmallChunks": {
+			maxChunkSizeBytes: 256,
+		},
+		"HugeChunks": {
+			maxChunkSizeBytes: 1024 * 1024 * 1024,
+			wantEmpty:         true,
+		},
+	} {
+		name, tc := name, tc
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var res bson.D
+			cmd := bson.D{
+				{"splitVector", ns},
+				{"keyPattern", bson.D{{"_id", int32(1)}}},
+				{"maxChunkSizeBytes", tc.maxChunkSizeBytes},
+			}
+			err := collection.Database().RunCommand(ctx, cmd).Decode(&res)
+			require.NoError(t, err)
+
+			m := res.Map()
+			splitKeys, ok := m["splitKeys"].(bson.A)
+			require.True(t, ok)
+
+			if tc.wantEmpty {
+				require.Empty(t, splitKeys)
+				return
+			}
+
+			require.NotEmpty(t, splitKeys)
+
+			// every split key must bound a range, and together the ranges
+			// must cover every document exactly once.
+			bounds := make([]int32, 0, len(splitKeys)+2)
+			bounds = append(bounds, 0)
+
+			for _, k := range splitKeys {
+				d, ok := k.(bson.D)
+				require.True(t, ok)
+				bounds = append(bounds, d.Map()["_id"].(int32))
+			}
+
+			bounds = append(bounds, docsCount)
+
+			var total int64
+
+			for i := 0; i < len(bounds)-1; i++ {
+				filter := bson.D{{"_id", bson.D{{"$gte", bounds[i]}, {"$lt", bounds[i+1]}}}}
+
+				count, err := collection.CountDocuments(ctx, filter)
+				require.NoError(t, err)
+
+				total += count
+			}
+
+			require.EqualValues(t, docsCount, total)
+		})
+	}
+
+	t.Run("EmptyCollection", func(t *testing.T) {
+		t.Parallel()
+
+		_, emptyCollection := setup.Setup(t)
+		emptyNs := emptyCollection.Database().Name() + "." + emptyCollection.Name()
+
+		var res bson.D
+		cmd := bson.D{
+			{"splitVector", emptyNs},
+			{"keyPattern", bson.D{{"_id", int32(1)}}},
+			{"maxChunkSizeBytes", int32(1024)},
+		}
+		err := emptyCollection.Database().RunCommand(ctx, cmd).Decode(&res)
+		require.NoError(t, err)
+
+		require.Empty(t, res.Map()["splitKeys"].(bson.A))
+	})
+
+	t.Run("NonIDKeyPattern", func(t *testing.T) {
+		t.Parallel()
+
+		var res bson.D
+		cmd := bson.D{
+			{"splitVector", ns},
+			{"keyPattern", bson.D{{"v", int32(1)}}},
+			{"maxChunkSizeBytes", int32(1024)},
+		}
+		err := collection.Database().RunCommand(ctx, cmd).Decode(&res)
+
+		var cmdErr mongo.CommandError
+		require.ErrorAs(t, err, &cmdErr)
+		require.Equal(t, "splitVector is only supported for the _id key pattern", cmdErr.Message)
+	})
+}
@@ -0,0 +1,408 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stages
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
+	"github.com/FerretDB/FerretDB/internal/handlers/common/aggregations"
+	"github.com/FerretDB/FerretDB/internal/handlers/commonerrors"
+	"github.com/FerretDB/FerretDB/internal/handlers/commonparams"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/iterator"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+)
+
+// bucketAuto represents $bucketAuto stage.
+//
+// Unlike $bucket, bucket boundaries aren't specified by the caller: the stage
+// sorts the input by groupBy and splits it into approximately bucketsCount
+// buckets of equal document count, growing a bucket past its target share
+// whenever the next document ties the current boundary value.
+type bucketAuto struct {
+	groupBy      *aggregations.Expression
+	bucketsCount int
+	output       *types.Document
+	granularity  string
+}
+
+// init registers $bucketAuto with the stage registry.
+func init() {
+	RegisterStage("$bucketAuto", newBucketAuto)
+}
+
+// newBucketAuto creates a new $bucketAuto stage.
+func newBucketAuto(stageDoc *types.Document) (Stage, error) {
+	fields, err := common.GetRequiredParam[*types.Document](stageDoc, "$bucketAuto")
+	if err != nil {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrStageBucketAutoInvalidArg,
+			"The $bucketAuto stage specification must be an object",
+			"$bucketAuto (stage)",
+		)
+	}
+
+	groupByField, err := fields.Get("groupBy")
+	if err != nil {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrStageBucketAutoMissingGroupBy,
+			"$bucketAuto requires a groupBy expression",
+			"$bucketAuto (stage)",
+		)
+	}
+
+	groupBy, err := aggregations.NewExpression(groupByField, nil)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	bucketsField, err := fields.Get("buckets")
+	if err != nil {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrStageBucketAutoInvalidArg,
+			"$bucketAuto requires a 'buckets' field",
+			"$bucketAuto (stage)",
+		)
+	}
+
+	bucketsCount, err := commonparams.GetWholeNumberParam(bucketsField)
+	if err != nil || bucketsCount <= 0 {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrStageBucketAutoInvalidArg,
+			"The $bucketAuto 'buckets' field must be a positive integer",
+			"$bucketAuto (stage)",
+		)
+	}
+
+	var output *types.Document
+	if v, err := fields.Get("output"); err == nil {
+		output, _ = v.(*types.Document)
+	}
+
+	var granularity string
+	if v, err := fields.Get("granularity"); err == nil {
+		granularity, _ = v.(string)
+	}
+
+	return &bucketAuto{
+		groupBy:      groupBy,
+		bucketsCount: int(bucketsCount),
+		output:       output,
+		granularity:  granularity,
+	}, nil
+}
+
+// keyed pairs a document with its already-evaluated groupBy value, so sorting
+// and bucketing don't need to re-run the groupBy expression.
+type keyed struct {
+	doc   *types.Document
+	value any
+}
+
+// Process implements Stage interface.
+func (b *bucketAuto) Process(ctx context.Context, in []*types.Document, closer *iterator.MultiCloser) ([]*types.Document, error) {
+	values := make([]keyed, 0, len(in))
+
+	for _, doc := range in {
+		v := b.groupBy.Evaluate(doc)
+		values = append(values, keyed{doc: doc, value: v})
+	}
+
+	sort.SliceStable(values, func(i, j int) bool {
+		return types.Compare(values[i].value, values[j].value) == types.Less
+	})
+
+	if len(values) == 0 {
+		return []*types.Document{}, nil
+	}
+
+	targetSize := len(values) / b.bucketsCount
+	if targetSize == 0 {
+		targetSize = 1
+	}
+
+	var buckets [][]keyed
+
+	start := 0
+	for start < len(values) {
+		end := start + targetSize
+		if end >= len(values) {
+			end = len(values)
+		} else {
+			// push ties on the boundary value into the same bucket,
+			// so a bucket can grow past targetSize
+			for end < len(values) && types.Compare(values[end].value, values[end-1].value) == types.Equal {
+				end++
+			}
+		}
+
+		buckets = append(buckets, values[start:end])
+		start = end
+	}
+
+	granularitySeries, err := granularityValues(b.granularity)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*types.Document, 0, len(buckets))
+
+	for _, bucket := range buckets {
+		min := bucket[0].value
+		max := bucket[len(bucket)-1].value
+
+		if granularitySeries != nil {
+			min = roundOutward(min, granularitySeries, false)
+			max = roundOutward(max, granularitySeries, true)
+		}
+
+		out := must.NotFail(types.NewDocument(
+			"_id", must.NotFail(types.NewDocument("min", min, "max", max)),
+			"count", int32(len(bucket)),
+		))
+
+		outputFields, err := computeOutput(bucket, b.output)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, k := range outputFields.Keys() {
+			out.Set(k, must.NotFail(outputFields.Get(k)))
+		}
+
+		res = append(res, out)
+	}
+
+	return res, nil
+}
+
+// computeOutput evaluates the $bucketAuto "output" accumulator document
+// against a single bucket's documents, returning the extra fields to merge
+// into that bucket's result document. It returns an empty document when
+// output is nil.
+func computeOutput(bucket []keyed, output *types.Document) (*types.Document, error) {
+	if output == nil {
+		return types.NewDocument()
+	}
+
+	result := types.MakeDocument(output.Len())
+
+	for _, field := range output.Keys() {
+		accDoc, ok := must.NotFail(output.Get(field)).(*types.Document)
+		if !ok || accDoc.Len() != 1 {
+			return nil, commonerrors.NewCommandErrorMsgWithArgument(
+				commonerrors.ErrStageBucketAutoInvalidArg,
+				"$bucketAuto output fields must be accumulator expressions, e.g. {$sum: 1}",
+				"$bucketAuto (stage)",
+			)
+		}
+
+		accName := accDoc.Keys()[0]
+
+		expr, err := aggregations.NewExpression(must.NotFail(accDoc.Get(accName)), nil)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		value, err := accumulate(accName, bucket, expr)
+		if err != nil {
+			return nil, err
+		}
+
+		result.Set(field, value)
+	}
+
+	return result, nil
+}
+
+// accumulate applies a single accumulator (e.g. "$sum", "$push") over every
+// document of bucket, evaluating expr against each one.
+func accumulate(accName string, bucket []keyed, expr *aggregations.Expression) (any, error) {
+	switch accName {
+	case "$sum":
+		var sum float64
+
+		for _, kv := range bucket {
+			if n, ok := toFloat64(expr.Evaluate(kv.doc)); ok {
+				sum += n
+			}
+		}
+
+		return sum, nil
+
+	case "$avg":
+		var sum float64
+
+		var count int
+
+		for _, kv := range bucket {
+			if n, ok := toFloat64(expr.Evaluate(kv.doc)); ok {
+				sum += n
+				count++
+			}
+		}
+
+		if count == 0 {
+			return nil, nil
+		}
+
+		return sum / float64(count), nil
+
+	case "$min", "$max":
+		want := types.Less
+		if accName == "$max" {
+			want = types.Greater
+		}
+
+		res := expr.Evaluate(bucket[0].doc)
+
+		for _, kv := range bucket[1:] {
+			v := expr.Evaluate(kv.doc)
+			if types.Compare(v, res) == want {
+				res = v
+			}
+		}
+
+		return res, nil
+
+	case "$first":
+		return expr.Evaluate(bucket[0].doc), nil
+
+	case "$last":
+		return expr.Evaluate(bucket[len(bucket)-1].doc), nil
+
+	case "$push":
+		arr := types.MakeArray(len(bucket))
+		for _, kv := range bucket {
+			arr.Append(expr.Evaluate(kv.doc))
+		}
+
+		return arr, nil
+
+	default:
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrNotImplemented,
+			"$bucketAuto output accumulator "+accName+" is not supported",
+			"$bucketAuto (stage)",
+		)
+	}
+}
+
+// toFloat64 converts a BSON numeric value to float64, reporting false for
+// non-numeric values so callers can skip them the way $sum/$avg do.
+func toFloat64(v any) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// granularityValues returns the preferred-number series for the given
+// granularity name, or nil if no granularity was requested.
+func granularityValues(granularity string) ([]float64, error) {
+	switch granularity {
+	case "":
+		return nil, nil
+	case "R5":
+		return []float64{1, 1.6, 2.5, 4, 6.3}, nil
+	case "POWERSOF2":
+		series := make([]float64, 0, 32)
+		for v := 1.0; v < 1e9; v *= 2 {
+			series = append(series, v)
+		}
+
+		return series, nil
+	default:
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrNotImplemented,
+			"Granularity "+granularity+" is not supported",
+			"$bucketAuto (stage)",
+		)
+	}
+}
+
+// roundOutward rounds v to the nearest value in series, rounding up (toward
+// larger magnitude) when up is true, or down otherwise. A series whose largest
+// value is below 10 (R5) holds one decade and is replicated across decades as
+// needed; any other series (POWERSOF2) is used as is.
+//
+// v may be any BSON numeric type; integers are rounded and converted back to
+// int64 rather than silently passed through, so granularity rounding also
+// applies to collections whose groupBy values happen to be integers.
+func roundOutward(v any, series []float64, up bool) any {
+	var f float64
+
+	var isInt bool
+
+	switch n := v.(type) {
+	case float64:
+		f = n
+	case int32:
+		f, isInt = float64(n), true
+	case int64:
+		f, isInt = float64(n), true
+	default:
+		return v
+	}
+
+	if f == 0 {
+		return v
+	}
+
+	decadeSeries := series[len(series)-1] < 10
+
+	decade := 1.0
+	if decadeSeries {
+		decade = math.Pow(10, math.Floor(math.Log10(math.Abs(f))))
+	}
+
+	rounded := f
+
+	if up {
+		if decadeSeries {
+			rounded = series[0] * decade * 10
+		}
+
+		for _, base := range series {
+			if candidate := base * decade; candidate >= f {
+				rounded = candidate
+				break
+			}
+		}
+	} else {
+		for i := len(series) - 1; i >= 0; i-- {
+			if candidate := series[i] * decade; candidate <= f {
+				rounded = candidate
+				break
+			}
+		}
+	}
+
+	if isInt {
+		return int64(math.Round(rounded))
+	}
+
+	return rounded
+}
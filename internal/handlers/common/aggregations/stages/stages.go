@@ -0,0 +1,75 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stages provides aggregation pipeline stages.
+package stages
+
+import (
+	"context"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/commonerrors"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/iterator"
+)
+
+// Stage is a common interface for aggregation pipeline stages.
+type Stage interface {
+	// Process applies the stage's transformation to in, closing any
+	// intermediate iterators it creates via closer, and returns the result.
+	Process(ctx context.Context, in []*types.Document, closer *iterator.MultiCloser) ([]*types.Document, error)
+}
+
+// newStageFunc constructs a Stage from its pipeline stage document.
+type newStageFunc func(stageDoc *types.Document) (Stage, error)
+
+// newStageFuncs maps aggregation stage names to their constructors. Each
+// stage registers itself from an init function via RegisterStage, so adding
+// a stage never requires editing this file.
+var newStageFuncs = map[string]newStageFunc{}
+
+// RegisterStage registers the constructor for a $-prefixed aggregation
+// pipeline stage name. It must be called from an init function of the file
+// implementing that stage, and panics if name is already registered.
+func RegisterStage(name string, newStage newStageFunc) {
+	if _, ok := newStageFuncs[name]; ok {
+		panic("stage " + name + " is already registered")
+	}
+
+	newStageFuncs[name] = newStage
+}
+
+// NewStage creates a new aggregation Stage from a pipeline stage document,
+// which must contain exactly one $-prefixed stage operator.
+func NewStage(stageDoc *types.Document) (Stage, error) {
+	if stageDoc.Len() != 1 {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrStageInvalidSpec,
+			"A pipeline stage specification object must contain exactly one field.",
+			"aggregate (stage)",
+		)
+	}
+
+	name := stageDoc.Keys()[0]
+
+	newStage, ok := newStageFuncs[name]
+	if !ok {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrNotImplemented,
+			"stage "+name+" is not implemented yet",
+			name+" (stage)",
+		)
+	}
+
+	return newStage(stageDoc)
+}
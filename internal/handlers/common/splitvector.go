@@ -0,0 +1,156 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package common
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/commonerrors"
+	"github.com/FerretDB/FerretDB/internal/handlers/commonparams"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/iterator"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+)
+
+// SplitVectorParams represents parameters for the splitVector command.
+type SplitVectorParams struct {
+	DB                string
+	Collection        string
+	KeyPattern        *types.Document
+	MaxChunkSizeBytes int64
+}
+
+// GetSplitVectorParams extracts and validates parameters for the splitVector command.
+func GetSplitVectorParams(document *types.Document) (*SplitVectorParams, error) {
+	ns, err := document.Get("splitVector")
+	if err != nil {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrMissingField,
+			"BSON field 'splitVector' is missing but a required field",
+			document.Command(),
+		)
+	}
+
+	namespace, ok := ns.(string)
+	if !ok {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrTypeMismatch,
+			"collection name has invalid type "+commonparams.AliasFromType(ns),
+			document.Command(),
+		)
+	}
+
+	parts := strings.SplitN(namespace, ".", 2)
+	if len(parts) != 2 {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrInvalidNamespace,
+			"Invalid namespace specified '"+namespace+"'",
+			document.Command(),
+		)
+	}
+
+	db, collection := parts[0], parts[1]
+
+	keyPattern := types.MakeDocument(1)
+	keyPattern.Set("_id", int32(1))
+
+	if v, _ := document.Get("keyPattern"); v != nil {
+		kp, ok := v.(*types.Document)
+		if !ok {
+			return nil, commonerrors.NewCommandErrorMsgWithArgument(
+				commonerrors.ErrTypeMismatch,
+				"keyPattern must be an object",
+				document.Command(),
+			)
+		}
+
+		keyPattern = kp
+	}
+
+	maxChunkSizeBytes := int64(32 * 1024 * 1024) // MongoDB's default chunk size.
+
+	if v, _ := document.Get("maxChunkSizeBytes"); v != nil {
+		size, err := commonparams.GetWholeNumberParam(v)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		maxChunkSizeBytes = size
+	}
+
+	return &SplitVectorParams{
+		DB:                db,
+		Collection:        collection,
+		KeyPattern:        keyPattern,
+		MaxChunkSizeBytes: maxChunkSizeBytes,
+	}, nil
+}
+
+// CalculateSplitKeysStreaming drains iter (already ordered by the splitVector key
+// pattern) and returns a split key every time the running total of estimated
+// document sizes crosses maxChunkSizeBytes. The last document never produces a
+// split key, matching MongoDB's behavior of returning ranges rather than every
+// boundary.
+//
+// Unlike a slice-based implementation, this never holds more than one pending
+// document in memory at a time, so collections far larger than maxChunkSizeBytes
+// can be split without buffering them whole.
+func CalculateSplitKeysStreaming(iter iterator.Interface[struct{}, *types.Document], keyField string, maxChunkSizeBytes int64) ([]any, error) {
+	if maxChunkSizeBytes <= 0 {
+		return nil, lazyerrors.New("maxChunkSizeBytes must be positive")
+	}
+
+	splitKeys := make([]any, 0, 16)
+
+	var accumulated int64
+	var pendingKey any
+	var hasPending bool
+
+	for {
+		_, doc, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, iterator.ErrIteratorDone) {
+				break
+			}
+
+			return nil, lazyerrors.Error(err)
+		}
+
+		// doc exists, so the previously pending boundary isn't the last
+		// document after all: commit it and start a fresh chunk.
+		if hasPending {
+			splitKeys = append(splitKeys, pendingKey)
+			hasPending = false
+			accumulated = 0
+		}
+
+		accumulated += int64(doc.Size())
+
+		if accumulated < maxChunkSizeBytes {
+			continue
+		}
+
+		key, err := doc.Get(keyField)
+		if err != nil {
+			return nil, lazyerrors.Error(err)
+		}
+
+		pendingKey = key
+		hasPending = true
+	}
+
+	return splitKeys, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package commonerrors provides errors shared by all handlers.
+package commonerrors
+
+import "fmt"
+
+// ErrorCode represents a MongoDB wire protocol compatible error code.
+type ErrorCode int32
+
+const (
+	// ErrNotImplemented indicates that the requested command or argument
+	// is recognized but not implemented yet.
+	ErrNotImplemented ErrorCode = 238
+
+	// ErrTypeMismatch indicates that a parameter has the wrong BSON type.
+	ErrTypeMismatch ErrorCode = 14
+
+	// ErrInvalidNamespace indicates that a "db.collection" namespace string is malformed.
+	ErrInvalidNamespace ErrorCode = 73
+
+	// ErrMissingField indicates that a required field is missing from a command document.
+	ErrMissingField ErrorCode = 40414
+
+	// ErrStageBucketAutoInvalidArg indicates an invalid $bucketAuto stage specification.
+	ErrStageBucketAutoInvalidArg ErrorCode = 5707200
+
+	// ErrStageBucketAutoMissingGroupBy indicates a $bucketAuto stage specification
+	// that is missing the required groupBy expression.
+	ErrStageBucketAutoMissingGroupBy ErrorCode = 5707201
+
+	// ErrStageInvalidSpec indicates that a pipeline stage specification document
+	// does not contain exactly one $-prefixed stage operator.
+	ErrStageInvalidSpec ErrorCode = 40323
+)
+
+// CommandError represents a MongoDB wire protocol command error: a code, a
+// human-readable message, and the name of the command/stage/argument that
+// triggered it.
+type CommandError struct {
+	Code     ErrorCode
+	Msg      string
+	Argument string
+}
+
+// Error implements the error interface.
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s: %s (code %d)", e.Argument, e.Msg, e.Code)
+}
+
+// NewCommandErrorMsgWithArgument creates a new CommandError with the given
+// code, message, and the name of the command/stage/argument that caused it.
+func NewCommandErrorMsgWithArgument(code ErrorCode, msg, argument string) error {
+	return &CommandError{Code: code, Msg: msg, Argument: argument}
+}
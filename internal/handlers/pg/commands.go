@@ -0,0 +1,54 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pg
+
+import (
+	"context"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/commonerrors"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// command is a handler for a single wire protocol command.
+type command func(*Handler, context.Context, *wire.OpMsg) (*wire.OpMsg, error)
+
+// commands maps command names to their handlers.
+var commands = map[string]command{
+	"splitVector": (*Handler).MsgSplitVector,
+}
+
+// Handle routes msg to the command handler registered for its command name
+// in commands, returning the handler's reply. It's the dispatch entry point
+// the wire listener calls into for every incoming OpMsg.
+func (h *Handler) Handle(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg, error) {
+	document, err := msg.Document()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	name := document.Command()
+
+	cmd, ok := commands[name]
+	if !ok {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrNotImplemented,
+			"no such command: '"+name+"'",
+			name,
+		)
+	}
+
+	return cmd(h, ctx, msg)
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 FerretDB Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pg
+
+import (
+	"context"
+
+	"github.com/FerretDB/FerretDB/internal/handlers/common"
+	"github.com/FerretDB/FerretDB/internal/handlers/commonerrors"
+	"github.com/FerretDB/FerretDB/internal/handlers/pg/pgdb"
+	"github.com/FerretDB/FerretDB/internal/types"
+	"github.com/FerretDB/FerretDB/internal/util/lazyerrors"
+	"github.com/FerretDB/FerretDB/internal/util/must"
+	"github.com/FerretDB/FerretDB/internal/wire"
+)
+
+// MsgSplitVector implements `splitVector` command.
+//
+// It lets ETL-style clients (e.g. Beam's MongoDB IO connector) partition a
+// collection into roughly-equal-sized chunks for parallel reads, without
+// requiring sharding. Only the `_id` key pattern is currently supported,
+// since that's the only index every collection is guaranteed to have.
+func (h *Handler) MsgSplitVector(ctx context.Context, msg *wire.OpMsg) (*wire.OpMsg, error) {
+	document, err := msg.Document()
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	params, err := common.GetSplitVectorParams(document)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := params.KeyPattern.Get("_id"); err != nil || params.KeyPattern.Len() != 1 {
+		return nil, commonerrors.NewCommandErrorMsgWithArgument(
+			commonerrors.ErrNotImplemented,
+			"splitVector is only supported for the _id key pattern",
+			document.Command(),
+		)
+	}
+
+	iter, err := h.pgPool.QueryDocuments(ctx, pgdb.QueryParams{
+		DB:         params.DB,
+		Collection: params.Collection,
+		Sort:       must.NotFail(types.NewDocument("_id", int32(1))),
+	})
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+	defer iter.Close()
+
+	// splitKeys is computed while streaming from iter rather than loading the
+	// whole collection into memory first, since splitVector exists precisely
+	// to support parallel reads over collections too large to buffer whole.
+	splitKeys, err := common.CalculateSplitKeysStreaming(iter, "_id", params.MaxChunkSizeBytes)
+	if err != nil {
+		return nil, lazyerrors.Error(err)
+	}
+
+	keys := types.MakeArray(len(splitKeys))
+	for _, k := range splitKeys {
+		keys.Append(must.NotFail(types.NewDocument("_id", k)))
+	}
+
+	var reply wire.OpMsg
+	must.NoError(reply.SetSections(wire.OpMsgSection{
+		Documents: []*types.Document{must.NotFail(types.NewDocument(
+			"splitKeys", keys,
+			"ok", float64(1),
+		))},
+	}))
+
+	return &reply, nil
+}